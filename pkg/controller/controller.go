@@ -1,12 +1,14 @@
 package controller
 
 import (
+	"context"
 	"time"
 
 	"k8s.io/klog"
 
 	"github.com/martinohmann/kube-volume-cleaner/pkg/listwatch"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -15,9 +17,14 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	appsv1listers "k8s.io/client-go/listers/apps/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 )
 
@@ -25,16 +32,58 @@ const (
 	StatefulSetLabel = "statefulset.kube-volume-cleaner.io/managed-by"
 )
 
+// RetentionPolicyMode selects how the controller expresses that a PVC is
+// managed by a StatefulSet.
+type RetentionPolicyMode string
+
+const (
+	// RetentionPolicyModeLabel stamps the StatefulSetLabel on the PVC.
+	// This is the original, backwards-compatible behavior.
+	RetentionPolicyModeLabel RetentionPolicyMode = "label"
+
+	// RetentionPolicyModeOwnerRef sets a blockOwnerDeletion ownerReference
+	// on the PVC instead of a label, mirroring what
+	// StatefulSet.Spec.PersistentVolumeClaimRetentionPolicy does natively
+	// on Kubernetes 1.23+.
+	RetentionPolicyModeOwnerRef RetentionPolicyMode = "ownerref"
+
+	// RetentionPolicyModeHybrid maintains both the label and the owner
+	// reference, which is useful while migrating existing installs.
+	RetentionPolicyModeHybrid RetentionPolicyMode = "hybrid"
+)
+
+func (m RetentionPolicyMode) usesLabel() bool {
+	return m == RetentionPolicyModeLabel || m == RetentionPolicyModeHybrid
+}
+
+func (m RetentionPolicyMode) usesOwnerRef() bool {
+	return m == RetentionPolicyModeOwnerRef || m == RetentionPolicyModeHybrid
+}
+
+var statefulSetKind = appsv1.SchemeGroupVersion.WithKind("StatefulSet")
+
 type Controller struct {
 	client kubernetes.Interface
 
-	podQueue workqueue.Interface
-	pvcQueue workqueue.Interface
-	setQueue workqueue.Interface
+	podQueue workqueue.RateLimitingInterface
+	pvcQueue workqueue.RateLimitingInterface
+	setQueue workqueue.RateLimitingInterface
 
-	namespace     string
-	labelSelector labels.Selector
-	dryRun        bool
+	namespace           string
+	labelSelector       labels.Selector
+	dryRun              bool
+	retentionPolicyMode RetentionPolicyMode
+	resyncPeriod        time.Duration
+
+	recorder record.EventRecorder
+	metrics  *Metrics
+
+	// reconcileCycles counts how many times runReconcilers has started.
+	// The first cycle reuses the queues/informers New built; later ones
+	// (after losing and reacquiring leadership) rebuild them, since
+	// neither a shut down queue nor a stopped SharedIndexInformer can be
+	// reused.
+	reconcileCycles int
 
 	podInformer cache.SharedIndexInformer
 	podLister   corev1listers.PodLister
@@ -46,45 +95,78 @@ type Controller struct {
 	setLister   appsv1listers.StatefulSetLister
 }
 
-func New(client kubernetes.Interface, namespace, selector string, dryRun bool) (*Controller, error) {
+// New constructs a Controller.
+func New(client kubernetes.Interface, metricsRegisterer prometheus.Registerer, namespace, selector string, dryRun bool, retentionPolicyMode RetentionPolicyMode, resyncPeriod time.Duration) (*Controller, error) {
 	labelSelector, err := labels.Parse(selector)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to parse label selector")
 	}
 
-	podListWatcher := listwatch.NewPodListWatch(client, namespace, labels.Everything())
-	podInformer := cache.NewSharedIndexInformer(podListWatcher, &corev1.Pod{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	podLister := corev1listers.NewPodLister(podInformer.GetIndexer())
+	switch retentionPolicyMode {
+	case RetentionPolicyModeLabel, RetentionPolicyModeOwnerRef, RetentionPolicyModeHybrid:
+	default:
+		return nil, errors.Errorf("invalid retention policy mode %q", retentionPolicyMode)
+	}
 
-	pvcListWatcher := listwatch.NewPersistentVolumeClaimListWatch(client, namespace, labels.Everything())
-	pvcInformer := cache.NewSharedIndexInformer(pvcListWatcher, &corev1.PersistentVolumeClaim{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	pvcLister := corev1listers.NewPersistentVolumeClaimLister(pvcInformer.GetIndexer())
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(klog.V(4).Infof)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events(namespace)})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "kube-volume-cleaner"})
 
-	setListWatcher := listwatch.NewStatefulSetListWatch(client, namespace, labels.Everything())
-	setInformer := cache.NewSharedIndexInformer(setListWatcher, &appsv1.StatefulSet{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	setLister := appsv1listers.NewStatefulSetLister(setInformer.GetIndexer())
+	if metricsRegisterer == nil {
+		metricsRegisterer = prometheus.DefaultRegisterer
+	}
 
 	c := &Controller{
-		client:        client,
-		podInformer:   podInformer,
-		podLister:     podLister,
-		podQueue:      workqueue.NewNamed("pod"),
-		pvcInformer:   pvcInformer,
-		pvcLister:     pvcLister,
-		pvcQueue:      workqueue.NewNamed("pvc"),
-		setInformer:   setInformer,
-		setLister:     setLister,
-		setQueue:      workqueue.NewNamed("statefulset"),
-		namespace:     namespace,
-		labelSelector: labelSelector,
-		dryRun:        dryRun,
+		client:              client,
+		namespace:           namespace,
+		labelSelector:       labelSelector,
+		dryRun:              dryRun,
+		resyncPeriod:        resyncPeriod,
+		recorder:            recorder,
+		metrics:             NewMetrics(metricsRegisterer),
+		retentionPolicyMode: retentionPolicyMode,
 	}
 
-	c.registerEventHandlers()
+	c.resetReconcileState()
 
 	return c, nil
 }
 
+// resetReconcileState (re)builds the queues, informers and listers a
+// reconcile cycle uses and registers their event handlers, replacing
+// whatever was built by a previous cycle.
+//
+// This exists because of leader election: SharedIndexInformer refuses to
+// run a second time once its stopCh has fired, and a shut down
+// workqueue.RateLimitingInterface stays shut down forever, so an instance
+// that loses and later reacquires leadership cannot reuse either one.
+// runReconcilers calls this at the start of every leadership cycle to get
+// a fresh set instead. Listers handed out before the first cycle even
+// starts (e.g. to the admission webhook via StatefulSetLister/PodLister)
+// stop receiving updates once the informer backing them is replaced;
+// callers that need to survive across leadership cycles should call the
+// accessor again after each OnStartedLeading rather than caching the
+// result.
+func (c *Controller) resetReconcileState() {
+	podListWatcher := listwatch.NewPodListWatch(c.client, c.namespace, labels.Everything())
+	c.podInformer = cache.NewSharedIndexInformer(podListWatcher, &corev1.Pod{}, c.resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c.podLister = corev1listers.NewPodLister(c.podInformer.GetIndexer())
+	c.podQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "pod")
+
+	pvcListWatcher := listwatch.NewPersistentVolumeClaimListWatch(c.client, c.namespace, labels.Everything())
+	c.pvcInformer = cache.NewSharedIndexInformer(pvcListWatcher, &corev1.PersistentVolumeClaim{}, c.resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c.pvcLister = corev1listers.NewPersistentVolumeClaimLister(c.pvcInformer.GetIndexer())
+	c.pvcQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "pvc")
+
+	setListWatcher := listwatch.NewStatefulSetListWatch(c.client, c.namespace, labels.Everything())
+	c.setInformer = cache.NewSharedIndexInformer(setListWatcher, &appsv1.StatefulSet{}, c.resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c.setLister = appsv1listers.NewStatefulSetLister(c.setInformer.GetIndexer())
+	c.setQueue = workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "statefulset")
+
+	c.registerEventHandlers()
+}
+
 func (c *Controller) registerEventHandlers() {
 	c.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: c.enqueuePod,
@@ -123,7 +205,7 @@ func (c *Controller) enqueueStatefulSet(obj interface{}) {
 	c.enqueue(c.setQueue, obj)
 }
 
-func (c *Controller) enqueue(queue workqueue.Interface, obj interface{}) {
+func (c *Controller) enqueue(queue workqueue.RateLimitingInterface, obj interface{}) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
 		klog.Errorf("failed to get key from object: %v", err)
@@ -135,9 +217,108 @@ func (c *Controller) enqueue(queue workqueue.Interface, obj interface{}) {
 	klog.V(5).Infof("enqueued %q for sync", key)
 }
 
-func (c *Controller) Run(stopCh <-chan struct{}) {
+// LeaderElectionConfig configures leader election for Controller.Run. A
+// nil config passed to Run disables leader election and the reconciler
+// loops start immediately, as if this instance was always the leader.
+type LeaderElectionConfig struct {
+	// LeaseName and LeaseNamespace identify the lock object used to
+	// coordinate leadership.
+	LeaseName      string
+	LeaseNamespace string
+
+	// Identity uniquely names this instance, e.g. its pod name.
+	Identity string
+
+	// ResourceLock is "leases" or "configmaps".
+	ResourceLock string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+// Run starts the controller's informers and reconciler loops and blocks
+// until stopCh is closed. If leConfig is non-nil, the reconciler loops
+// only run while this instance holds leadership, so that multiple
+// replicas can be run for HA without racing each other.
+func (c *Controller) Run(stopCh <-chan struct{}, leConfig *LeaderElectionConfig) error {
+	if leConfig == nil {
+		c.runReconcilers(stopCh)
+		return nil
+	}
+
+	lock, err := resourcelock.New(
+		leConfig.ResourceLock,
+		leConfig.LeaseNamespace,
+		leConfig.LeaseName,
+		c.client.CoreV1(),
+		c.client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: leConfig.Identity},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to construct leader election resource lock")
+	}
+
+	leCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	leConf := leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: leConfig.LeaseDuration,
+		RenewDeadline: leConfig.RenewDeadline,
+		RetryPeriod:   leConfig.RetryPeriod,
+		// Release the lease immediately on a graceful shutdown instead of
+		// making a standby wait out the full LeaseDuration to fail over.
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				klog.Infof("%s: started leading", leConfig.Identity)
+
+				reconcileStopCh := make(chan struct{})
+				go func() {
+					select {
+					case <-stopCh:
+					case <-ctx.Done():
+					}
+					close(reconcileStopCh)
+				}()
+
+				c.runReconcilers(reconcileStopCh)
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("%s: stopped leading", leConfig.Identity)
+			},
+		},
+	}
+
+	// RunOrDie returns as soon as this instance loses leadership, so loop
+	// around it until stopCh is closed to keep retrying for the lease
+	// instead of leaving this replica permanently idle.
+	for leCtx.Err() == nil {
+		leaderelection.RunOrDie(leCtx, leConf)
+	}
+
+	return nil
+}
+
+// runReconcilers runs the informers and worker loops until stopCh is
+// closed. It is the part of Run that must only execute on the leader
+// when leader election is enabled.
+func (c *Controller) runReconcilers(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 
+	// The very first cycle reuses the queues/informers New built. Every
+	// later cycle (after losing and reacquiring leadership) needs a
+	// fresh set instead: a SharedIndexInformer refuses to run again once
+	// its stopCh has fired, and a shut down queue stays shut down.
+	if c.reconcileCycles > 0 {
+		c.resetReconcileState()
+	}
+	c.reconcileCycles++
+
 	defer c.podQueue.ShutDown()
 	defer c.pvcQueue.ShutDown()
 	defer c.setQueue.ShutDown()
@@ -154,15 +335,20 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 		return
 	}
 
-	go wait.Until(worker(c.podQueue, c.syncPod), time.Second, stopCh)
-	go wait.Until(worker(c.pvcQueue, c.syncVolumeClaim), time.Second, stopCh)
-	go wait.Until(worker(c.setQueue, c.syncStatefulSet), time.Second, stopCh)
+	go wait.Until(worker("pod", c.podQueue, c.syncPod, c.metrics), time.Second, stopCh)
+	go wait.Until(worker("pvc", c.pvcQueue, c.syncVolumeClaim, c.metrics), time.Second, stopCh)
+	go wait.Until(worker("statefulset", c.setQueue, c.syncStatefulSet, c.metrics), time.Second, stopCh)
 
 	<-stopCh
 	klog.Info("stopping controller")
 }
 
-func worker(queue workqueue.Interface, syncFunc func(string) error) func() {
+// maxRetries bounds how many times a key is requeued with backoff after a
+// failed sync before it is dropped, so a consistently broken item doesn't
+// retry forever.
+const maxRetries = 15
+
+func worker(queueName string, queue workqueue.RateLimitingInterface, syncFunc func(string) error, m *Metrics) func() {
 	return func() {
 		workFunc := func() bool {
 			key, quit := queue.Get()
@@ -171,8 +357,24 @@ func worker(queue workqueue.Interface, syncFunc func(string) error) func() {
 			}
 			defer queue.Done(key)
 
+			start := time.Now()
 			err := syncFunc(key.(string))
-			utilruntime.HandleError(err)
+			m.ReconcileDurationSeconds.WithLabelValues(queueName).Observe(time.Since(start).Seconds())
+
+			switch {
+			case err == nil:
+				queue.Forget(key)
+			case queue.NumRequeues(key) < maxRetries:
+				m.ReconcileErrorsTotal.WithLabelValues(queueName).Inc()
+				klog.V(1).Infof("error syncing %q from %s queue (retry %d/%d): %v", key, queueName, queue.NumRequeues(key)+1, maxRetries, err)
+				queue.AddRateLimited(key)
+			default:
+				m.ReconcileErrorsTotal.WithLabelValues(queueName).Inc()
+				klog.Errorf("dropping %q from %s queue after %d failed attempts: %v", key, queueName, maxRetries+1, err)
+				queue.Forget(key)
+				utilruntime.HandleError(err)
+			}
+
 			return false
 		}
 
@@ -256,7 +458,12 @@ func (c *Controller) syncVolumeClaim(key string) error {
 
 	pvc, err := c.pvcLister.PersistentVolumeClaims(namespace).Get(name)
 	if err == nil {
-		return c.handleVolumeClaimUpdate(pvc)
+		if err := c.handleVolumeClaimUpdate(pvc); err != nil {
+			c.recorder.Eventf(pvc, corev1.EventTypeWarning, "SyncError", "failed to sync pvc: %v", err)
+			return err
+		}
+
+		return nil
 	}
 
 	if !apierrors.IsNotFound(err) {
@@ -290,10 +497,10 @@ func (c *Controller) handleVolumeClaimUpdate(pvc *corev1.PersistentVolumeClaim)
 		// statefulset does not exist anymore, it is safe to delete.
 		klog.V(4).Infof("pvc %s/%s is not mounted to a pod, checking if it should be deleted", pvc.Namespace, pvc.Name)
 
-		setName, exists := getStatefulSetLabel(pvc)
+		setName, exists := c.getManagingStatefulSetName(pvc)
 		if !exists {
 			// do we need to do something here?
-			klog.V(4).Infof("pvc %s/%s does not have label %s, no candidate for deletion", pvc.Namespace, pvc.Name, StatefulSetLabel)
+			klog.V(4).Infof("pvc %s/%s has no statefulset retention reference, no candidate for deletion", pvc.Namespace, pvc.Name)
 			return nil
 		}
 
@@ -301,11 +508,11 @@ func (c *Controller) handleVolumeClaimUpdate(pvc *corev1.PersistentVolumeClaim)
 		if err == nil {
 			if isMatchingSelector(set, c.labelSelector) {
 				klog.V(4).Infof("statefulset %s/%s managing pvc %s/%s still present, not deleting pvc", set.Namespace, set.Name, pvc.Namespace, pvc.Name)
-				return nil
+				return c.retainPVC(pvc, set)
 			}
 
 			klog.V(5).Infof("statefulset %s/%s managing pvc %s/%s does not match label selector %q", set.Namespace, set.Name, pvc.Namespace, pvc.Name, c.labelSelector.String())
-			return c.removeStatefulSetLabel(pvc)
+			return c.releaseRetention(pvc)
 		}
 
 		if !apierrors.IsNotFound(err) {
@@ -316,12 +523,21 @@ func (c *Controller) handleVolumeClaimUpdate(pvc *corev1.PersistentVolumeClaim)
 		// we are safe to delete the pvc.
 		if c.dryRun {
 			klog.Infof("would delete pvc %s/%s, but dry run is enabled", pvc.Namespace, pvc.Name)
+			c.recorder.Event(pvc, corev1.EventTypeWarning, "DeletionSkipped", "pvc would be deleted, but dry run is enabled")
+			c.metrics.PVCDeletedTotal.WithLabelValues(pvc.Namespace, "true").Inc()
 			return nil
 		}
 
 		klog.Infof("deleting pvc %s/%s", pvc.Namespace, pvc.Name)
 
-		return c.client.CoreV1().PersistentVolumeClaims(pvc.Namespace).Delete(pvc.Name, &metav1.DeleteOptions{})
+		if err := c.client.CoreV1().PersistentVolumeClaims(pvc.Namespace).Delete(pvc.Name, &metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+
+		c.metrics.PVCDeletedTotal.WithLabelValues(pvc.Namespace, "false").Inc()
+		c.recorder.Event(pvc, corev1.EventTypeNormal, "Deleted", "pvc deleted, no longer mounted by a pod of a matching statefulset")
+
+		return nil
 	}
 
 	set, err := c.getStatefulSetForPod(pod)
@@ -332,50 +548,143 @@ func (c *Controller) handleVolumeClaimUpdate(pvc *corev1.PersistentVolumeClaim)
 		return err
 	case set == nil:
 		klog.V(4).Infof("pod mounting pvc %s/%s does not belong to statefulset", pvc.Namespace, pvc.Name)
-		return c.removeStatefulSetLabel(pvc)
+		return c.releaseRetention(pvc)
 	case !isMatchingSelector(set, c.labelSelector):
 		klog.V(5).Infof("statefulset %s/%s controlling pod %s/%s does not match label selector %q", set.Namespace, set.Name, pod.Namespace, pod.Name, c.labelSelector.String())
-		return c.removeStatefulSetLabel(pvc)
+		return c.releaseRetention(pvc)
 	}
 
-	return c.updateStatefulSetLabel(pvc, set.Name)
+	return c.retainPVC(pvc, set)
 }
 
-func (c *Controller) removeStatefulSetLabel(pvc *corev1.PersistentVolumeClaim) error {
-	_, found := getStatefulSetLabel(pvc)
-	if !found {
-		return nil
-	}
+// getManagingStatefulSetName returns the name of the StatefulSet that
+// currently claims retention ownership of pvc. It looks at the owner
+// reference first and falls back to the legacy label, whatever
+// c.retentionPolicyMode is.
+//
+// The legacy label is always consulted as a fallback, even in pure
+// ownerref mode: a PVC that was labeled before the operator switched
+// retention-policy-mode straight from label to ownerref, and whose pod
+// hasn't come back since to trigger migration, otherwise looks ownerless
+// forever instead of being picked up and migrated by retainPVC.
+func (c *Controller) getManagingStatefulSetName(pvc *corev1.PersistentVolumeClaim) (string, bool) {
+	if name, ok := getStatefulSetOwnerRefName(pvc); ok {
+		return name, true
+	}
+
+	return getStatefulSetLabel(pvc)
+}
 
+// retainPVC stamps pvc with whatever retention markers c.retentionPolicyMode
+// calls for (label, ownerReference, or both), migrating PVCs that only
+// carry the legacy label to an ownerReference as a side effect.
+func (c *Controller) retainPVC(pvc *corev1.PersistentVolumeClaim, set *appsv1.StatefulSet) error {
 	pvcCopy := pvc.DeepCopy()
+	changed := false
 
-	delete(pvcCopy.Labels, StatefulSetLabel)
+	if c.retentionPolicyMode.usesLabel() {
+		oldValue, exists := getStatefulSetLabel(pvcCopy)
+		if !exists && pvcCopy.Labels == nil {
+			pvcCopy.Labels = map[string]string{}
+		}
+
+		if pvcCopy.Labels[StatefulSetLabel] != set.Name {
+			pvcCopy.Labels[StatefulSetLabel] = set.Name
+			changed = true
+
+			if oldValue == "" {
+				klog.Infof("adding label %q on pvc %s/%s: %q", StatefulSetLabel, pvc.Namespace, pvc.Name, set.Name)
+				c.recorder.Eventf(pvc, corev1.EventTypeNormal, "LabelAdded", "added label %s=%s", StatefulSetLabel, set.Name)
+			} else {
+				klog.Infof("updating label %q on pvc %s/%s: %q -> %q", StatefulSetLabel, pvc.Namespace, pvc.Name, oldValue, set.Name)
+				c.recorder.Eventf(pvc, corev1.EventTypeNormal, "LabelAdded", "updated label %s: %s -> %s", StatefulSetLabel, oldValue, set.Name)
+			}
 
-	klog.Infof("removing label %q from pvc %s/%s", StatefulSetLabel, pvc.Namespace, pvc.Name)
+			c.metrics.PVCLabelUpdatedTotal.Inc()
+		}
+	}
+
+	if c.retentionPolicyMode.usesOwnerRef() && setStatefulSetOwnerRef(pvcCopy, set) {
+		changed = true
+		klog.Infof("setting ownerReference on pvc %s/%s to statefulset %s/%s", pvc.Namespace, pvc.Name, set.Namespace, set.Name)
+	}
+
+	if !changed {
+		return nil
+	}
 
 	return c.syncVolumeClaimUpdate(pvcCopy)
 }
 
-func (c *Controller) updateStatefulSetLabel(pvc *corev1.PersistentVolumeClaim, newValue string) error {
+// releaseRetention strips whatever retention markers c.retentionPolicyMode
+// maintains from pvc.
+func (c *Controller) releaseRetention(pvc *corev1.PersistentVolumeClaim) error {
 	pvcCopy := pvc.DeepCopy()
+	changed := false
+
+	if c.retentionPolicyMode.usesLabel() {
+		if _, found := getStatefulSetLabel(pvcCopy); found {
+			delete(pvcCopy.Labels, StatefulSetLabel)
+			changed = true
+			klog.Infof("removing label %q from pvc %s/%s", StatefulSetLabel, pvc.Namespace, pvc.Name)
+			c.recorder.Eventf(pvc, corev1.EventTypeNormal, "LabelRemoved", "removed label %s", StatefulSetLabel)
+		}
+	}
 
-	oldValue, exists := getStatefulSetLabel(pvcCopy)
-	if !exists && pvcCopy.Labels == nil {
-		pvcCopy.Labels = map[string]string{}
+	if c.retentionPolicyMode.usesOwnerRef() && removeStatefulSetOwnerRef(pvcCopy) {
+		changed = true
+		klog.Infof("removing statefulset ownerReference from pvc %s/%s", pvc.Namespace, pvc.Name)
 	}
 
-	pvcCopy.Labels[StatefulSetLabel] = newValue
-	if newValue == oldValue {
+	if !changed {
 		return nil
 	}
 
-	if oldValue == "" {
-		klog.Infof("adding label %q on pvc %s/%s: %q", StatefulSetLabel, pvc.Namespace, pvc.Name, newValue)
-	} else {
-		klog.Infof("updating label %q on pvc %s/%s: %q -> %q", StatefulSetLabel, pvc.Namespace, pvc.Name, oldValue, newValue)
+	return c.syncVolumeClaimUpdate(pvcCopy)
+}
+
+// getStatefulSetOwnerRefName returns the name of the StatefulSet
+// controller-owning pvc, if any.
+func getStatefulSetOwnerRefName(pvc *corev1.PersistentVolumeClaim) (string, bool) {
+	for _, ref := range pvc.OwnerReferences {
+		if isStatefulSetOwnerRef(&ref) {
+			return ref.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// setStatefulSetOwnerRef adds a blockOwnerDeletion ownerReference to set on
+// pvc, unless one is already present. It reports whether it changed pvc.
+func setStatefulSetOwnerRef(pvc *corev1.PersistentVolumeClaim, set *appsv1.StatefulSet) bool {
+	if _, found := getStatefulSetOwnerRefName(pvc); found {
+		return false
 	}
 
-	return c.syncVolumeClaimUpdate(pvcCopy)
+	pvc.OwnerReferences = append(pvc.OwnerReferences, *metav1.NewControllerRef(set, statefulSetKind))
+
+	return true
+}
+
+// removeStatefulSetOwnerRef strips any StatefulSet controller ownerReference
+// from pvc. It reports whether it changed pvc.
+func removeStatefulSetOwnerRef(pvc *corev1.PersistentVolumeClaim) bool {
+	refs := make([]metav1.OwnerReference, 0, len(pvc.OwnerReferences))
+	removed := false
+
+	for _, ref := range pvc.OwnerReferences {
+		if isStatefulSetOwnerRef(&ref) {
+			removed = true
+			continue
+		}
+
+		refs = append(refs, ref)
+	}
+
+	pvc.OwnerReferences = refs
+
+	return removed
 }
 
 func (c *Controller) syncVolumeClaimUpdate(pvc *corev1.PersistentVolumeClaim) error {
@@ -490,9 +799,17 @@ func (c *Controller) getStatefulSet(namespace, name string) (*appsv1.StatefulSet
 	return c.setLister.StatefulSets(namespace).Get(name)
 }
 
+// getStatefulSetForPod only resolves StatefulSet owners. A generic
+// owner-resolver for arbitrary kinds (see 9fb69f4) was tried and then
+// fully reverted (317c074): retainPVC/releaseRetention only understand
+// StatefulSet, so any pod whose owner resolved to a different kind was
+// treated as ownerless and had its PVC's retention protection silently
+// stripped. That request is intentionally descoped rather than
+// implemented; reintroducing it requires teaching retention handling
+// about non-StatefulSet owners first.
 func (c *Controller) getStatefulSetForPod(pod *corev1.Pod) (*appsv1.StatefulSet, error) {
 	ownerRef := metav1.GetControllerOf(pod)
-	if !isStatefulSetOwnerRef(ownerRef) {
+	if ownerRef == nil || !isStatefulSetOwnerRef(ownerRef) {
 		return nil, nil
 	}
 
@@ -542,3 +859,31 @@ func (c *Controller) getVolumeClaimsForStatefulSet(namespace, name string) ([]*c
 
 	return c.pvcLister.PersistentVolumeClaims(namespace).List(selector)
 }
+
+// StatefulSetLister exposes the controller's StatefulSet cache so other
+// components (e.g. the admission webhook) can reuse it instead of
+// standing up a second informer.
+func (c *Controller) StatefulSetLister() appsv1listers.StatefulSetLister {
+	return c.setLister
+}
+
+// PodLister exposes the controller's Pod cache so other components (e.g.
+// the admission webhook) can reuse it instead of standing up a second
+// informer.
+func (c *Controller) PodLister() corev1listers.PodLister {
+	return c.podLister
+}
+
+// LabelSelector returns the label selector statefulsets must match to be
+// considered managed by the controller.
+func (c *Controller) LabelSelector() labels.Selector {
+	return c.labelSelector
+}
+
+// NewStatefulSetOwnerReference builds the blockOwnerDeletion ownerReference
+// the controller stamps on PVCs it retains for set, for reuse by other
+// components that need to apply it ahead of the controller's own
+// reconcile loop (e.g. the admission webhook).
+func NewStatefulSetOwnerReference(set *appsv1.StatefulSet) metav1.OwnerReference {
+	return *metav1.NewControllerRef(set, statefulSetKind)
+}
@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestWorkerRetriesUpToMaxThenDrops(t *testing.T) {
+	const key = "default/foo"
+
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 5*time.Millisecond), "test")
+	metrics := NewMetrics(prometheus.NewRegistry())
+
+	var mu sync.Mutex
+	calls := 0
+
+	syncFunc := func(string) error {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		if n == maxRetries+1 {
+			// the worker should give up and drop the key at this point;
+			// shutting down the queue here lets the worker loop exit
+			// instead of retrying forever if it doesn't.
+			queue.ShutDown()
+		}
+
+		return errors.New("synthetic failure")
+	}
+
+	queue.Add(key)
+
+	done := make(chan struct{})
+	go func() {
+		worker("test", queue, syncFunc, metrics)()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop after the queue was shut down")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if calls != maxRetries+1 {
+		t.Errorf("syncFunc was called %d times, want %d (1 initial attempt + %d retries)", calls, maxRetries+1, maxRetries)
+	}
+}
+
+func TestWorkerForgetsKeyOnSuccess(t *testing.T) {
+	const key = "default/foo"
+
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+	metrics := NewMetrics(prometheus.NewRegistry())
+
+	var mu sync.Mutex
+	calls := 0
+	synced := make(chan struct{}, 1)
+
+	syncFunc := func(string) error {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+
+		synced <- struct{}{}
+
+		return nil
+	}
+
+	queue.Add(key)
+
+	done := make(chan struct{})
+	go func() {
+		worker("test", queue, syncFunc, metrics)()
+		close(done)
+	}()
+
+	select {
+	case <-synced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("syncFunc was never called")
+	}
+
+	// give the worker a chance to act on the result (Forget vs.
+	// AddRateLimited) before the queue is shut down out from under it.
+	time.Sleep(50 * time.Millisecond)
+	queue.ShutDown()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop after the queue was shut down")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if calls != 1 {
+		t.Errorf("syncFunc was called %d times, want 1: a successful sync should Forget the key instead of requeuing it", calls)
+	}
+}
+
+// TestRunReconcilersAcrossReacquiredLeadership exercises the scenario
+// Run's leadership retry loop creates: runReconcilers is started, stopped
+// (as happens on losing leadership), and started again (as happens on
+// reacquiring it). The second cycle must still actually observe cluster
+// state instead of silently doing nothing with a stopped informer and a
+// shut down queue.
+func TestRunReconcilersAcrossReacquiredLeadership(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	c, err := New(client, prometheus.NewRegistry(), "default", "", false, RetentionPolicyModeLabel, 0)
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+
+	runCycle := func() {
+		stopCh := make(chan struct{})
+
+		done := make(chan struct{})
+		go func() {
+			c.runReconcilers(stopCh)
+			close(done)
+		}()
+
+		if !cache.WaitForCacheSync(stopCh, c.podInformer.HasSynced, c.pvcInformer.HasSynced, c.setInformer.HasSynced) {
+			t.Fatalf("caches did not sync")
+		}
+
+		close(stopCh)
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("runReconcilers did not stop after stopCh was closed")
+		}
+	}
+
+	// First leadership cycle.
+	runCycle()
+
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data-web-0"}}
+	if _, err := client.CoreV1().PersistentVolumeClaims("default").Create(pvc); err != nil {
+		t.Fatalf("failed to create pvc: %v", err)
+	}
+
+	// Second leadership cycle, as happens after losing and reacquiring
+	// the lease. If runReconcilers reused the first cycle's (now stopped)
+	// informer and (now shut down) queue, this would never observe the
+	// pvc created above.
+	runCycle()
+
+	got, err := c.pvcLister.PersistentVolumeClaims("default").Get("data-web-0")
+	if err != nil {
+		t.Fatalf("second reconcile cycle's pvcLister could not find the pvc created between cycles: %v", err)
+	}
+
+	if got.Name != pvc.Name {
+		t.Errorf("got pvc %q, want %q", got.Name, pvc.Name)
+	}
+}
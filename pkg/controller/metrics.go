@@ -0,0 +1,47 @@
+package controller
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics bundles the Prometheus collectors the controller reports on
+// /metrics. They are registered against a caller-supplied registerer so
+// that a binary embedding multiple controllers doesn't collide on the
+// default global registry.
+type Metrics struct {
+	PVCDeletedTotal          *prometheus.CounterVec
+	PVCLabelUpdatedTotal     prometheus.Counter
+	ReconcileErrorsTotal     *prometheus.CounterVec
+	ReconcileDurationSeconds *prometheus.HistogramVec
+}
+
+// NewMetrics creates and registers the controller's Prometheus
+// collectors against reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		PVCDeletedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kvc_pvc_deleted_total",
+			Help: "Total number of PVCs deleted by the controller.",
+		}, []string{"namespace", "dry_run"}),
+		PVCLabelUpdatedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "kvc_pvc_label_updated_total",
+			Help: "Total number of times the controller updated a PVC's statefulset label.",
+		}),
+		ReconcileErrorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kvc_reconcile_errors_total",
+			Help: "Total number of reconcile errors, by queue.",
+		}, []string{"queue"}),
+		ReconcileDurationSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "kvc_reconcile_duration_seconds",
+			Help:    "Time spent reconciling a single item, by queue.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"queue"}),
+	}
+
+	reg.MustRegister(
+		m.PVCDeletedTotal,
+		m.PVCLabelUpdatedTotal,
+		m.ReconcileErrorsTotal,
+		m.ReconcileDurationSeconds,
+	)
+
+	return m
+}
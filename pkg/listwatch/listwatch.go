@@ -0,0 +1,74 @@
+// Package listwatch builds cache.ListWatchs for the resources the
+// controller and resizer packages watch, so informer construction doesn't
+// have to repeat the same typed-client boilerplate at every call site.
+package listwatch
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewPodListWatch returns a cache.ListWatch for Pods in namespace matching
+// selector.
+func NewPodListWatch(client kubernetes.Interface, namespace string, selector labels.Selector) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector.String()
+			return client.CoreV1().Pods(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector.String()
+			return client.CoreV1().Pods(namespace).Watch(options)
+		},
+	}
+}
+
+// NewPersistentVolumeClaimListWatch returns a cache.ListWatch for
+// PersistentVolumeClaims in namespace matching selector.
+func NewPersistentVolumeClaimListWatch(client kubernetes.Interface, namespace string, selector labels.Selector) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector.String()
+			return client.CoreV1().PersistentVolumeClaims(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector.String()
+			return client.CoreV1().PersistentVolumeClaims(namespace).Watch(options)
+		},
+	}
+}
+
+// NewStatefulSetListWatch returns a cache.ListWatch for StatefulSets in
+// namespace matching selector.
+func NewStatefulSetListWatch(client kubernetes.Interface, namespace string, selector labels.Selector) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector.String()
+			return client.AppsV1().StatefulSets(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector.String()
+			return client.AppsV1().StatefulSets(namespace).Watch(options)
+		},
+	}
+}
+
+// NewStorageClassListWatch returns a cache.ListWatch for StorageClasses
+// matching selector. StorageClasses are cluster-scoped, so unlike the
+// list-watchers above there is no namespace to filter on.
+func NewStorageClassListWatch(client kubernetes.Interface, selector labels.Selector) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = selector.String()
+			return client.StorageV1().StorageClasses().List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector.String()
+			return client.StorageV1().StorageClasses().Watch(options)
+		},
+	}
+}
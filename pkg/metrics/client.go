@@ -0,0 +1,36 @@
+// Package metrics provides access to per-volume filesystem usage
+// statistics so that callers can make resize decisions without caring
+// where the numbers actually come from (kubelet, metrics-server,
+// Prometheus, ...).
+package metrics
+
+import (
+	"context"
+)
+
+// VolumeUsage holds the filesystem usage of a single PersistentVolumeClaim
+// as reported by a Client. It mirrors the fields kubelet exposes via
+// kubelet_volume_stats_used_bytes / kubelet_volume_stats_capacity_bytes.
+type VolumeUsage struct {
+	UsedBytes     int64
+	CapacityBytes int64
+}
+
+// Fraction returns the used capacity as a value between 0 and 1. It
+// returns 0 if CapacityBytes is not positive.
+func (u VolumeUsage) Fraction() float64 {
+	if u.CapacityBytes <= 0 {
+		return 0
+	}
+
+	return float64(u.UsedBytes) / float64(u.CapacityBytes)
+}
+
+// Client retrieves volume usage statistics for a PersistentVolumeClaim
+// mounted on a given node. Implementations may source the data from
+// kubelet's summary API, metrics-server or Prometheus.
+type Client interface {
+	// VolumeUsage returns the current filesystem usage of the PVC
+	// identified by namespace/pvcName as observed on nodeName.
+	VolumeUsage(ctx context.Context, nodeName, namespace, pvcName string) (*VolumeUsage, error)
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// summaryAPIPath is the path kubelet exposes the node/pod/volume usage
+// summary on, proxied through the api-server.
+const summaryAPIPath = "/stats/summary"
+
+// summary mirrors the subset of kubelet's stats/summary.Summary type we
+// care about. It is kept minimal on purpose so this client does not need
+// to vendor kubelet's internal stats package.
+type summary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		VolumeStats []struct {
+			Name   string `json:"name"`
+			PVCRef *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+			UsedBytes int64 `json:"usedBytes"`
+			Capacity  int64 `json:"capacityBytes"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// KubeletClient implements Client by scraping the kubelet summary API of
+// each node through the api-server's node proxy, i.e. it requires no
+// direct network access to the nodes themselves.
+type KubeletClient struct {
+	client kubernetes.Interface
+}
+
+// NewKubeletClient returns a Client backed by kubelet's /stats/summary
+// endpoint.
+func NewKubeletClient(client kubernetes.Interface) *KubeletClient {
+	return &KubeletClient{client: client}
+}
+
+// VolumeUsage implements Client.
+func (c *KubeletClient) VolumeUsage(ctx context.Context, nodeName, namespace, pvcName string) (*VolumeUsage, error) {
+	raw, err := c.client.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix(summaryAPIPath).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to scrape kubelet summary API on node %q", nodeName)
+	}
+
+	var s summary
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode kubelet summary API response from node %q", nodeName)
+	}
+
+	for _, pod := range s.Pods {
+		if pod.PodRef.Namespace != namespace {
+			continue
+		}
+
+		for _, vol := range pod.VolumeStats {
+			if vol.PVCRef == nil || vol.PVCRef.Name != pvcName || vol.PVCRef.Namespace != namespace {
+				continue
+			}
+
+			return &VolumeUsage{UsedBytes: vol.UsedBytes, CapacityBytes: vol.Capacity}, nil
+		}
+	}
+
+	return nil, errors.Errorf("no volume stats found for pvc %s/%s on node %q", namespace, pvcName, nodeName)
+}
@@ -0,0 +1,117 @@
+package resizer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ThresholdAnnotation configures the usage percentage (e.g. "80%") at
+	// which a PVC becomes a candidate for resizing.
+	ThresholdAnnotation = "volume-autoresizer.io/threshold"
+
+	// IncrementAnnotation configures how much storage is added on every
+	// resize. It accepts either a quantity (e.g. "10Gi") or a growth
+	// factor expressed as a percentage (e.g. "20%") of the current size.
+	IncrementAnnotation = "volume-autoresizer.io/increment"
+
+	// MaxSizeAnnotation caps the size a PVC may be grown to.
+	MaxSizeAnnotation = "volume-autoresizer.io/max-size"
+
+	// CooldownAnnotation overrides the global cooldown for a single PVC.
+	CooldownAnnotation = "volume-autoresizer.io/cooldown"
+
+	// defaultIncrement is used when IncrementAnnotation is absent.
+	defaultIncrement = "10%"
+)
+
+// threshold returns the configured usage threshold for pvc as a fraction
+// between 0 and 1, and whether the PVC opted into autoresizing at all.
+func threshold(pvc *corev1.PersistentVolumeClaim) (float64, bool) {
+	raw, ok := pvc.Annotations[ThresholdAnnotation]
+	if !ok {
+		return 0, false
+	}
+
+	frac, err := parsePercent(raw)
+	if err != nil {
+		return 0, false
+	}
+
+	return frac, true
+}
+
+// maxSize returns the configured upper bound for pvc's storage request,
+// if any.
+func maxSize(pvc *corev1.PersistentVolumeClaim) (resource.Quantity, bool) {
+	raw, ok := pvc.Annotations[MaxSizeAnnotation]
+	if !ok {
+		return resource.Quantity{}, false
+	}
+
+	q, err := resource.ParseQuantity(raw)
+	if err != nil {
+		return resource.Quantity{}, false
+	}
+
+	return q, true
+}
+
+// cooldown returns the per-PVC cooldown override, if configured.
+func cooldown(pvc *corev1.PersistentVolumeClaim) (time.Duration, bool) {
+	raw, ok := pvc.Annotations[CooldownAnnotation]
+	if !ok {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, false
+	}
+
+	return d, true
+}
+
+// nextSize computes the new storage request for pvc given its current
+// capacity, honoring the configured increment and max-size.
+func nextSize(pvc *corev1.PersistentVolumeClaim, current resource.Quantity) resource.Quantity {
+	raw, ok := pvc.Annotations[IncrementAnnotation]
+	if !ok {
+		raw = defaultIncrement
+	}
+
+	next := current.DeepCopy()
+
+	if frac, err := parsePercent(raw); err == nil && strings.HasSuffix(strings.TrimSpace(raw), "%") {
+		delta := int64(float64(current.Value()) * frac)
+		next.Add(*resource.NewQuantity(delta, current.Format))
+	} else if inc, err := resource.ParseQuantity(raw); err == nil {
+		next.Add(inc)
+	} else {
+		// invalid annotation, do not grow the volume.
+		return current
+	}
+
+	if max, ok := maxSize(pvc); ok && next.Cmp(max) > 0 {
+		return max
+	}
+
+	return next
+}
+
+// parsePercent parses strings like "80%" into a fraction between 0 and 1.
+func parsePercent(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, "%")
+
+	pct, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return pct / 100, nil
+}
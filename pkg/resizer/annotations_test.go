@@ -0,0 +1,176 @@
+package resizer
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func pvcWithAnnotations(annotations map[string]string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{name: "plain percent", in: "80%", want: 0.8},
+		{name: "whitespace", in: " 20% ", want: 0.2},
+		{name: "no percent sign", in: "50", want: 0.5},
+		{name: "zero", in: "0%", want: 0},
+		{name: "invalid", in: "not-a-number", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePercent(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePercent(%q) expected error, got nil", tt.in)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parsePercent(%q) returned unexpected error: %v", tt.in, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("parsePercent(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	tests := []struct {
+		name   string
+		pvc    *corev1.PersistentVolumeClaim
+		want   float64
+		wantOk bool
+	}{
+		{name: "missing annotation", pvc: pvcWithAnnotations(nil), wantOk: false},
+		{
+			name:   "valid threshold",
+			pvc:    pvcWithAnnotations(map[string]string{ThresholdAnnotation: "75%"}),
+			want:   0.75,
+			wantOk: true,
+		},
+		{
+			name:   "invalid threshold is treated as opted-out",
+			pvc:    pvcWithAnnotations(map[string]string{ThresholdAnnotation: "garbage"}),
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := threshold(tt.pvc)
+			if ok != tt.wantOk {
+				t.Fatalf("threshold() ok = %v, want %v", ok, tt.wantOk)
+			}
+
+			if ok && got != tt.want {
+				t.Errorf("threshold() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	tests := []struct {
+		name   string
+		pvc    *corev1.PersistentVolumeClaim
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "missing annotation", pvc: pvcWithAnnotations(nil), wantOk: false},
+		{
+			name:   "valid duration",
+			pvc:    pvcWithAnnotations(map[string]string{CooldownAnnotation: "10m"}),
+			want:   10 * time.Minute,
+			wantOk: true,
+		},
+		{
+			name:   "invalid duration",
+			pvc:    pvcWithAnnotations(map[string]string{CooldownAnnotation: "soon"}),
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := cooldown(tt.pvc)
+			if ok != tt.wantOk {
+				t.Fatalf("cooldown() ok = %v, want %v", ok, tt.wantOk)
+			}
+
+			if ok && got != tt.want {
+				t.Errorf("cooldown() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		pvc     *corev1.PersistentVolumeClaim
+		current string
+		want    string
+	}{
+		{
+			name:    "default increment",
+			pvc:     pvcWithAnnotations(nil),
+			current: "100Gi",
+			want:    "110Gi",
+		},
+		{
+			name:    "percent increment",
+			pvc:     pvcWithAnnotations(map[string]string{IncrementAnnotation: "20%"}),
+			current: "100Gi",
+			want:    "120Gi",
+		},
+		{
+			name:    "quantity increment",
+			pvc:     pvcWithAnnotations(map[string]string{IncrementAnnotation: "10Gi"}),
+			current: "100Gi",
+			want:    "110Gi",
+		},
+		{
+			name: "capped at max-size",
+			pvc: pvcWithAnnotations(map[string]string{
+				IncrementAnnotation: "50%",
+				MaxSizeAnnotation:   "120Gi",
+			}),
+			current: "100Gi",
+			want:    "120Gi",
+		},
+		{
+			name:    "invalid increment does not grow",
+			pvc:     pvcWithAnnotations(map[string]string{IncrementAnnotation: "garbage"}),
+			current: "100Gi",
+			want:    "100Gi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := resource.MustParse(tt.current)
+			want := resource.MustParse(tt.want)
+
+			got := nextSize(tt.pvc, current)
+			if got.Cmp(want) != 0 {
+				t.Errorf("nextSize() = %s, want %s", got.String(), want.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+// Package resizer watches PersistentVolumeClaims managed by the
+// autoresizer and grows them in place once their filesystem usage
+// crosses a configured threshold.
+package resizer
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/martinohmann/kube-volume-cleaner/pkg/listwatch"
+	"github.com/martinohmann/kube-volume-cleaner/pkg/metrics"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	storagev1listers "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// resizingConditions are the PVC conditions that must be absent before
+// another resize of the same PVC may be attempted.
+var resizingConditions = map[corev1.PersistentVolumeClaimConditionType]bool{
+	corev1.PersistentVolumeClaimResizing:                true,
+	corev1.PersistentVolumeClaimFileSystemResizePending: true,
+}
+
+// Resizer periodically inspects the usage of PVCs annotated with
+// ThresholdAnnotation and grows them once they cross it.
+type Resizer struct {
+	client        kubernetes.Interface
+	metricsClient metrics.Client
+
+	namespace     string
+	labelSelector labels.Selector
+	syncPeriod    time.Duration
+	cooldown      time.Duration
+	dryRun        bool
+
+	queue workqueue.Interface
+
+	podInformer cache.SharedIndexInformer
+	podLister   corev1listers.PodLister
+
+	pvcInformer cache.SharedIndexInformer
+	pvcLister   corev1listers.PersistentVolumeClaimLister
+
+	scInformer cache.SharedIndexInformer
+	scLister   storagev1listers.StorageClassLister
+
+	mu         sync.Mutex
+	lastResize map[string]time.Time
+}
+
+// New returns a Resizer that grows PVCs in namespace matching selector
+// once their usage crosses their configured threshold. metricsClient
+// supplies the actual usage numbers and may be backed by kubelet,
+// metrics-server or Prometheus.
+func New(client kubernetes.Interface, metricsClient metrics.Client, namespace, selector string, syncPeriod, cooldown time.Duration, dryRun bool) (*Resizer, error) {
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse label selector")
+	}
+
+	podListWatcher := listwatch.NewPodListWatch(client, namespace, labels.Everything())
+	podInformer := cache.NewSharedIndexInformer(podListWatcher, &corev1.Pod{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	podLister := corev1listers.NewPodLister(podInformer.GetIndexer())
+
+	pvcListWatcher := listwatch.NewPersistentVolumeClaimListWatch(client, namespace, labels.Everything())
+	pvcInformer := cache.NewSharedIndexInformer(pvcListWatcher, &corev1.PersistentVolumeClaim{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	pvcLister := corev1listers.NewPersistentVolumeClaimLister(pvcInformer.GetIndexer())
+
+	scListWatcher := listwatch.NewStorageClassListWatch(client, labels.Everything())
+	scInformer := cache.NewSharedIndexInformer(scListWatcher, &storagev1.StorageClass{}, 0, cache.Indexers{})
+	scLister := storagev1listers.NewStorageClassLister(scInformer.GetIndexer())
+
+	r := &Resizer{
+		client:        client,
+		metricsClient: metricsClient,
+		namespace:     namespace,
+		labelSelector: labelSelector,
+		syncPeriod:    syncPeriod,
+		cooldown:      cooldown,
+		dryRun:        dryRun,
+		queue:         workqueue.NewNamed("resizer"),
+		podInformer:   podInformer,
+		podLister:     podLister,
+		pvcInformer:   pvcInformer,
+		pvcLister:     pvcLister,
+		scInformer:    scInformer,
+		scLister:      scLister,
+		lastResize:    map[string]time.Time{},
+	}
+
+	r.pvcInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: r.enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			r.enqueue(newObj)
+		},
+		DeleteFunc: r.forgetLastResize,
+	})
+
+	return r, nil
+}
+
+func (r *Resizer) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.Errorf("failed to get key from object: %v", err)
+		return
+	}
+
+	r.queue.Add(key)
+}
+
+// forgetLastResize drops a deleted PVC's cooldown bookkeeping so
+// r.lastResize doesn't grow without bound under routine PVC churn.
+func (r *Resizer) forgetLastResize(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.Errorf("failed to get key from object: %v", err)
+		return
+	}
+
+	r.mu.Lock()
+	delete(r.lastResize, key)
+	r.mu.Unlock()
+}
+
+// Run starts the informers and the periodic scrape loop. It blocks until
+// stopCh is closed.
+func (r *Resizer) Run(stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer r.queue.ShutDown()
+
+	klog.Info("starting resizer")
+
+	go r.podInformer.Run(stopCh)
+	go r.pvcInformer.Run(stopCh)
+	go r.scInformer.Run(stopCh)
+
+	if !cache.WaitForCacheSync(stopCh, r.podInformer.HasSynced, r.pvcInformer.HasSynced, r.scInformer.HasSynced) {
+		utilruntime.HandleError(errors.Errorf("timed out waiting for caches to sync"))
+		return
+	}
+
+	go wait.Until(r.worker, time.Second, stopCh)
+	go wait.Until(r.scanAll, r.syncPeriod, stopCh)
+
+	<-stopCh
+	klog.Info("stopping resizer")
+}
+
+func (r *Resizer) worker() {
+	for {
+		key, quit := r.queue.Get()
+		if quit {
+			return
+		}
+
+		err := r.syncKey(key.(string))
+		utilruntime.HandleError(err)
+		r.queue.Done(key)
+	}
+}
+
+// scanAll enqueues every PVC matching labelSelector so scrapes keep
+// happening even when the informer is quiet. PVCs without a threshold
+// annotation are skipped later, by syncVolumeClaim.
+func (r *Resizer) scanAll() {
+	pvcs, err := r.pvcLister.PersistentVolumeClaims(r.namespace).List(r.labelSelector)
+	if err != nil {
+		klog.Errorf("failed to list pvcs for resize scan: %v", err)
+		return
+	}
+
+	for _, pvc := range pvcs {
+		r.enqueue(pvc)
+	}
+}
+
+func (r *Resizer) syncKey(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	pvc, err := r.pvcLister.PersistentVolumeClaims(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return r.syncVolumeClaim(pvc)
+}
+
+func (r *Resizer) syncVolumeClaim(pvc *corev1.PersistentVolumeClaim) error {
+	if !r.labelSelector.Matches(labels.Set(pvc.Labels)) {
+		return nil
+	}
+
+	frac, ok := threshold(pvc)
+	if !ok {
+		return nil
+	}
+
+	if !r.expansionAllowed(pvc) {
+		klog.V(4).Infof("pvc %s/%s's storageclass does not allow volume expansion, skipping", pvc.Namespace, pvc.Name)
+		return nil
+	}
+
+	if hasCondition(pvc, resizingConditions) {
+		klog.V(4).Infof("pvc %s/%s has a resize in progress, skipping", pvc.Namespace, pvc.Name)
+		return nil
+	}
+
+	if !r.cooldownElapsed(pvc) {
+		klog.V(4).Infof("pvc %s/%s is within its cooldown period, skipping", pvc.Namespace, pvc.Name)
+		return nil
+	}
+
+	pod, err := r.getPodForVolumeClaim(pvc)
+	if err != nil {
+		return err
+	}
+
+	if pod == nil || pod.Spec.NodeName == "" {
+		klog.V(4).Infof("pvc %s/%s is not mounted to a scheduled pod, nothing to scrape", pvc.Namespace, pvc.Name)
+		return nil
+	}
+
+	usage, err := r.metricsClient.VolumeUsage(context.Background(), pod.Spec.NodeName, pvc.Namespace, pvc.Name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get volume usage for pvc %s/%s", pvc.Namespace, pvc.Name)
+	}
+
+	if usage.Fraction() < frac {
+		return nil
+	}
+
+	current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+
+	next := nextSize(pvc, current)
+	if next.Cmp(current) <= 0 {
+		klog.V(4).Infof("pvc %s/%s cannot grow (max size reached or invalid increment), not resizing", pvc.Namespace, pvc.Name)
+		return nil
+	}
+
+	if r.dryRun {
+		klog.Infof("would resize pvc %s/%s from %s to %s (usage %.0f%%), but dry run is enabled", pvc.Namespace, pvc.Name, current.String(), next.String(), usage.Fraction()*100)
+		return nil
+	}
+
+	klog.Infof("resizing pvc %s/%s from %s to %s (usage %.0f%%)", pvc.Namespace, pvc.Name, current.String(), next.String(), usage.Fraction()*100)
+
+	if err := r.patchStorageRequest(pvc, next); err != nil {
+		return err
+	}
+
+	r.recordResize(pvc)
+
+	return nil
+}
+
+func (r *Resizer) patchStorageRequest(pvc *corev1.PersistentVolumeClaim, size resource.Quantity) error {
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{
+					"storage": size.String(),
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal resize patch")
+	}
+
+	_, err = r.client.CoreV1().PersistentVolumeClaims(pvc.Namespace).Patch(pvc.Name, types.MergePatchType, data)
+
+	return err
+}
+
+func (r *Resizer) expansionAllowed(pvc *corev1.PersistentVolumeClaim) bool {
+	scName := pvc.Spec.StorageClassName
+	if scName == nil || *scName == "" {
+		return false
+	}
+
+	sc, err := r.scLister.Get(*scName)
+	if err != nil {
+		klog.V(1).Infof("failed to get storageclass %q for pvc %s/%s: %v", *scName, pvc.Namespace, pvc.Name, err)
+		return false
+	}
+
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion
+}
+
+func (r *Resizer) cooldownElapsed(pvc *corev1.PersistentVolumeClaim) bool {
+	d := r.cooldown
+	if override, ok := cooldown(pvc); ok {
+		d = override
+	}
+
+	r.mu.Lock()
+	last, ok := r.lastResize[cacheKey(pvc)]
+	r.mu.Unlock()
+
+	if !ok {
+		return true
+	}
+
+	return time.Since(last) >= d
+}
+
+func (r *Resizer) recordResize(pvc *corev1.PersistentVolumeClaim) {
+	r.mu.Lock()
+	r.lastResize[cacheKey(pvc)] = time.Now()
+	r.mu.Unlock()
+}
+
+func (r *Resizer) getPodForVolumeClaim(pvc *corev1.PersistentVolumeClaim) (*corev1.Pod, error) {
+	pods, err := r.podLister.Pods(pvc.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods {
+		if podHasVolumeClaim(pod, pvc.Name) {
+			return pod, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func podHasVolumeClaim(pod *corev1.Pod, claimName string) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasCondition(pvc *corev1.PersistentVolumeClaim, types map[corev1.PersistentVolumeClaimConditionType]bool) bool {
+	for _, cond := range pvc.Status.Conditions {
+		if types[cond.Type] && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
+func cacheKey(pvc *corev1.PersistentVolumeClaim) string {
+	return pvc.Namespace + "/" + pvc.Name
+}
@@ -0,0 +1,314 @@
+// Package webhook exposes an admission webhook server that mutates and
+// validates PersistentVolumeClaims at creation time, complementing the
+// informer-based Controller by removing the lag between a PVC being
+// created and the controller's next reconcile observing it.
+package webhook
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+
+	"k8s.io/klog"
+
+	"github.com/martinohmann/kube-volume-cleaner/pkg/controller"
+	"github.com/pkg/errors"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+)
+
+var (
+	scheme = runtime.NewScheme()
+	codecs = serializer.NewCodecFactory(scheme)
+
+	// pvcOrdinal matches the ordinal suffix kubernetes appends to a
+	// StatefulSet's volumeClaimTemplate name when naming its PVCs.
+	pvcOrdinal = regexp.MustCompile(`^[0-9]+$`)
+)
+
+func init() {
+	if err := admissionv1.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+}
+
+// Server is the admission webhook's HTTPS server. It reuses the
+// Controller's StatefulSet and Pod caches instead of maintaining its own.
+type Server struct {
+	setLister appsv1listers.StatefulSetLister
+	podLister corev1listers.PodLister
+
+	labelSelector       labels.Selector
+	retentionPolicyMode controller.RetentionPolicyMode
+
+	// controllerServiceAccount is the "system:serviceaccount:<ns>:<name>"
+	// identity the Controller runs as. Deletions of a mounted PVC are
+	// rejected unless they originate from this identity.
+	controllerServiceAccount string
+
+	tlsCertFile, tlsKeyFile string
+	port                    int
+}
+
+// New returns a webhook Server. setLister and podLister are typically
+// Controller.StatefulSetLister() and Controller.PodLister() so the
+// webhook shares the controller's caches rather than starting its own
+// informers.
+func New(
+	client kubernetes.Interface,
+	setLister appsv1listers.StatefulSetLister,
+	podLister corev1listers.PodLister,
+	labelSelector labels.Selector,
+	retentionPolicyMode controller.RetentionPolicyMode,
+	controllerServiceAccount string,
+	tlsCertFile, tlsKeyFile string,
+	port int,
+) *Server {
+	return &Server{
+		setLister:                setLister,
+		podLister:                podLister,
+		labelSelector:            labelSelector,
+		retentionPolicyMode:      retentionPolicyMode,
+		controllerServiceAccount: controllerServiceAccount,
+		tlsCertFile:              tlsCertFile,
+		tlsKeyFile:               tlsKeyFile,
+		port:                     port,
+	}
+}
+
+// Run starts the HTTPS server and blocks until stopCh is closed.
+func (s *Server) Run(stopCh <-chan struct{}) error {
+	cert, err := tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load webhook TLS certificate")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutate", s.serve(s.mutate))
+	mux.HandleFunc("/validate", s.serve(s.validate))
+
+	srv := &http.Server{
+		Addr:      fmt.Sprintf(":%d", s.port),
+		Handler:   mux,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+
+	go func() {
+		<-stopCh
+		_ = srv.Close()
+	}()
+
+	klog.Infof("starting admission webhook server on %s", srv.Addr)
+
+	if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) serve(admit func(*admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, errors.Wrap(err, "failed to read admission request body").Error(), http.StatusBadRequest)
+			return
+		}
+
+		review := &admissionv1.AdmissionReview{}
+		if _, _, err := codecs.UniversalDeserializer().Decode(body, nil, review); err != nil {
+			http.Error(w, errors.Wrap(err, "failed to decode admission review").Error(), http.StatusBadRequest)
+			return
+		}
+
+		response := admit(review.Request)
+		response.UID = review.Request.UID
+
+		review.Response = response
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(review)
+	}
+}
+
+// mutate stamps the StatefulSetLabel (and/or ownerReference, depending on
+// the configured retention policy mode) on PVCs created by a StatefulSet
+// matching labelSelector, so the retention marker is present from the
+// very first observation of the PVC instead of racing the controller's
+// informer-driven reconcile.
+func (s *Server) mutate(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Resource.Resource != "persistentvolumeclaims" || req.Operation != admissionv1.Create {
+		return allowed()
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := json.Unmarshal(req.Object.Raw, pvc); err != nil {
+		return denied(errors.Wrap(err, "failed to decode pvc"))
+	}
+
+	set, err := s.statefulSetForPVCName(pvc.Namespace, pvc.Name)
+	if err != nil {
+		return denied(err)
+	}
+
+	if set == nil || !isMatchingSelector(set, s.labelSelector) {
+		return allowed()
+	}
+
+	patch := s.retentionPatch(pvc, set)
+	if len(patch) == 0 {
+		return allowed()
+	}
+
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return denied(errors.Wrap(err, "failed to marshal retention patch"))
+	}
+
+	patchType := admissionv1.PatchTypeJSONPatch
+
+	return &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     patchBytes,
+		PatchType: &patchType,
+	}
+}
+
+// retentionPatch builds the JSON patch operations needed to stamp pvc
+// with the retention markers for set, according to the configured
+// retention policy mode.
+func (s *Server) retentionPatch(pvc *corev1.PersistentVolumeClaim, set *appsv1.StatefulSet) []map[string]interface{} {
+	var patch []map[string]interface{}
+
+	if s.retentionPolicyMode == controller.RetentionPolicyModeLabel || s.retentionPolicyMode == controller.RetentionPolicyModeHybrid {
+		if len(pvc.Labels) == 0 {
+			patch = append(patch, map[string]interface{}{"op": "add", "path": "/metadata/labels", "value": map[string]string{}})
+		}
+
+		// "add" both creates and overwrites a map key, so it covers the
+		// label-already-present case too; "replace" would fail here
+		// whenever volumeClaimTemplate sets other labels but not this one.
+		patch = append(patch, map[string]interface{}{
+			"op":    "add",
+			"path":  "/metadata/labels/" + jsonPointerEscape(controller.StatefulSetLabel),
+			"value": set.Name,
+		})
+	}
+
+	if s.retentionPolicyMode == controller.RetentionPolicyModeOwnerRef || s.retentionPolicyMode == controller.RetentionPolicyModeHybrid {
+		if len(pvc.OwnerReferences) == 0 {
+			patch = append(patch, map[string]interface{}{"op": "add", "path": "/metadata/ownerReferences", "value": []interface{}{}})
+		}
+
+		patch = append(patch, map[string]interface{}{
+			"op":    "add",
+			"path":  "/metadata/ownerReferences/-",
+			"value": controller.NewStatefulSetOwnerReference(set),
+		})
+	}
+
+	return patch
+}
+
+// validate rejects deletions of a PVC that is still mounted by a running
+// pod unless the caller is the controller's own ServiceAccount, closing
+// the race where an external actor deletes a PVC the controller would
+// otherwise have protected.
+func (s *Server) validate(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Resource.Resource != "persistentvolumeclaims" || req.Operation != admissionv1.Delete {
+		return allowed()
+	}
+
+	if req.UserInfo.Username == s.controllerServiceAccount {
+		return allowed()
+	}
+
+	pods, err := s.podLister.Pods(req.Namespace).List(labels.Everything())
+	if err != nil {
+		return denied(errors.Wrap(err, "failed to list pods"))
+	}
+
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == req.Name {
+				return denied(errors.Errorf("pvc %s/%s is still mounted by pod %s", req.Namespace, req.Name, pod.Name))
+			}
+		}
+	}
+
+	return allowed()
+}
+
+// statefulSetForPVCName guesses the StatefulSet that will own a PVC named
+// pvcName by matching it against each candidate StatefulSet's
+// volumeClaimTemplates, the same "<template>-<statefulset>-<ordinal>"
+// naming scheme the StatefulSet controller itself uses.
+func (s *Server) statefulSetForPVCName(namespace, pvcName string) (*appsv1.StatefulSet, error) {
+	sets, err := s.setLister.StatefulSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list statefulsets")
+	}
+
+	for _, set := range sets {
+		for _, tmpl := range set.Spec.VolumeClaimTemplates {
+			prefix := tmpl.Name + "-" + set.Name + "-"
+			if len(pvcName) <= len(prefix) || pvcName[:len(prefix)] != prefix {
+				continue
+			}
+
+			if pvcOrdinal.MatchString(pvcName[len(prefix):]) {
+				return set, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func jsonPointerEscape(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch r {
+		case '~':
+			out = append(out, '~', '0')
+		case '/':
+			out = append(out, '~', '1')
+		default:
+			out = append(out, r)
+		}
+	}
+
+	return string(out)
+}
+
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func denied(err error) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result:  &metav1.Status{Message: err.Error()},
+	}
+}
+
+// isMatchingSelector reports whether set's labels satisfy selector.
+func isMatchingSelector(set *appsv1.StatefulSet, selector labels.Selector) bool {
+	return selector.Matches(labels.Set(set.Labels))
+}
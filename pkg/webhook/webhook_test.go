@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/martinohmann/kube-volume-cleaner/pkg/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+)
+
+func TestJSONPointerEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "foo", want: "foo"},
+		{name: "slash", in: "statefulset.kube-volume-cleaner.io/managed-by", want: "statefulset.kube-volume-cleaner.io~1managed-by"},
+		{name: "tilde", in: "a~b", want: "a~0b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonPointerEscape(tt.in); got != tt.want {
+				t.Errorf("jsonPointerEscape(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetentionPatch(t *testing.T) {
+	set := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "web"}}
+
+	tests := []struct {
+		name      string
+		mode      controller.RetentionPolicyMode
+		pvc       *corev1.PersistentVolumeClaim
+		wantOps   []string
+		wantPaths []string
+	}{
+		{
+			name:      "label mode, no existing labels",
+			mode:      controller.RetentionPolicyModeLabel,
+			pvc:       &corev1.PersistentVolumeClaim{},
+			wantOps:   []string{"add", "add"},
+			wantPaths: []string{"/metadata/labels", "/metadata/labels/statefulset.kube-volume-cleaner.io~1managed-by"},
+		},
+		{
+			name: "label mode, other labels already present",
+			mode: controller.RetentionPolicyModeLabel,
+			pvc: &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+			},
+			wantOps:   []string{"add"},
+			wantPaths: []string{"/metadata/labels/statefulset.kube-volume-cleaner.io~1managed-by"},
+		},
+		{
+			name:      "ownerref mode, no existing ownerReferences",
+			mode:      controller.RetentionPolicyModeOwnerRef,
+			pvc:       &corev1.PersistentVolumeClaim{},
+			wantOps:   []string{"add", "add"},
+			wantPaths: []string{"/metadata/ownerReferences", "/metadata/ownerReferences/-"},
+		},
+		{
+			name: "ownerref mode, ownerReferences already present",
+			mode: controller.RetentionPolicyModeOwnerRef,
+			pvc: &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{{Name: "other"}}},
+			},
+			wantOps:   []string{"add"},
+			wantPaths: []string{"/metadata/ownerReferences/-"},
+		},
+		{
+			name: "hybrid mode, other labels already present",
+			mode: controller.RetentionPolicyModeHybrid,
+			pvc: &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+			},
+			wantOps:   []string{"add", "add", "add"},
+			wantPaths: []string{"/metadata/labels/statefulset.kube-volume-cleaner.io~1managed-by", "/metadata/ownerReferences", "/metadata/ownerReferences/-"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{retentionPolicyMode: tt.mode}
+
+			patch := s.retentionPatch(tt.pvc, set)
+			if len(patch) != len(tt.wantOps) {
+				t.Fatalf("retentionPatch() returned %d ops, want %d: %+v", len(patch), len(tt.wantOps), patch)
+			}
+
+			for i := range tt.wantOps {
+				if patch[i]["op"] != tt.wantOps[i] {
+					t.Errorf("patch[%d][\"op\"] = %v, want %q", i, patch[i]["op"], tt.wantOps[i])
+				}
+
+				if patch[i]["path"] != tt.wantPaths[i] {
+					t.Errorf("patch[%d][\"path\"] = %v, want %q", i, patch[i]["path"], tt.wantPaths[i])
+				}
+			}
+
+			applyJSONPatchAdds(t, tt.pvc, patch)
+		})
+	}
+}
+
+// applyJSONPatchAdds simulates applying patch's "add" operations to pvc the
+// way the apiserver's json-patch library would, failing the test if any op
+// targets a path whose parent container doesn't exist yet (the exact class
+// of bug this patch has twice needed fixing for).
+func applyJSONPatchAdds(t *testing.T, pvc *corev1.PersistentVolumeClaim, patch []map[string]interface{}) {
+	t.Helper()
+
+	labelsSet := len(pvc.Labels) > 0
+	ownerRefsSet := len(pvc.OwnerReferences) > 0
+
+	for _, op := range patch {
+		if op["op"] != "add" {
+			t.Fatalf("unexpected op %v, applyJSONPatchAdds only understands \"add\"", op["op"])
+		}
+
+		switch path := op["path"].(string); {
+		case path == "/metadata/labels":
+			if labelsSet {
+				t.Fatalf("add %q would overwrite the existing labels map instead of merging into it", path)
+			}
+			labelsSet = true
+		case strings.HasPrefix(path, "/metadata/labels/"):
+			if !labelsSet {
+				t.Fatalf("add %q targets a labels map that was never created by an earlier op", path)
+			}
+		case path == "/metadata/ownerReferences":
+			if ownerRefsSet {
+				t.Fatalf("add %q would overwrite the existing ownerReferences array instead of appending to it", path)
+			}
+			ownerRefsSet = true
+		case path == "/metadata/ownerReferences/-":
+			if !ownerRefsSet {
+				t.Fatalf("add %q appends to an ownerReferences array that was never created by an earlier op", path)
+			}
+		default:
+			t.Fatalf("unexpected patch path %q", path)
+		}
+	}
+}
+
+func TestStatefulSetForPVCName(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	set := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "data"}},
+			},
+		},
+	}
+
+	if err := indexer.Add(set); err != nil {
+		t.Fatalf("failed to seed indexer: %v", err)
+	}
+
+	s := &Server{setLister: appsv1listers.NewStatefulSetLister(indexer)}
+
+	tests := []struct {
+		name    string
+		pvcName string
+		want    string
+	}{
+		{name: "matches template name and ordinal", pvcName: "data-web-0", want: "web"},
+		{name: "different statefulset name", pvcName: "data-other-0", want: ""},
+		{name: "non-numeric ordinal", pvcName: "data-web-abc", want: ""},
+		{name: "too short", pvcName: "data-web-", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.statefulSetForPVCName("default", tt.pvcName)
+			if err != nil {
+				t.Fatalf("statefulSetForPVCName() returned unexpected error: %v", err)
+			}
+
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("statefulSetForPVCName() = %v, want nil", got)
+				}
+				return
+			}
+
+			if got == nil || got.Name != tt.want {
+				t.Errorf("statefulSetForPVCName() = %v, want statefulset %q", got, tt.want)
+			}
+		})
+	}
+}